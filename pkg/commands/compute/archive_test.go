@@ -0,0 +1,253 @@
+package compute
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixtureFiles returns the content of the two files every test package in
+// this file is built from: testdata/pkg/fastly.toml and testdata/pkg/main.wasm.
+func fixtureFiles(t testing.TB) map[string][]byte {
+	t.Helper()
+
+	files := map[string][]byte{}
+	for _, name := range []string{"fastly.toml", "main.wasm"} {
+		data, err := os.ReadFile(filepath.Join("testdata", "pkg", name))
+		if err != nil {
+			t.Fatalf("error reading fixture %s: %v", name, err)
+		}
+		files[name] = data
+	}
+	return files
+}
+
+// writeTarGzFixture builds a tar.gz package at path from the given files.
+func writeTarGzFixture(t testing.TB, path string, files map[string][]byte) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("error creating fixture: %v", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	defer gz.Close()
+
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	for name, data := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(data)), Mode: 0o644}); err != nil {
+			t.Fatalf("error writing tar header for %s: %v", name, err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatalf("error writing tar content for %s: %v", name, err)
+		}
+	}
+}
+
+// writeTarFixture builds an uncompressed tar package at path.
+func writeTarFixture(t testing.TB, path string, files map[string][]byte) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("error creating fixture: %v", err)
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	defer tw.Close()
+
+	for name, data := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(data)), Mode: 0o644}); err != nil {
+			t.Fatalf("error writing tar header for %s: %v", name, err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatalf("error writing tar content for %s: %v", name, err)
+		}
+	}
+}
+
+// writeZipFixture builds a zip package at path.
+func writeZipFixture(t testing.TB, path string, files map[string][]byte) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("error creating fixture: %v", err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	defer zw.Close()
+
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("error creating zip entry for %s: %v", name, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("error writing zip content for %s: %v", name, err)
+		}
+	}
+}
+
+// readAllEntries drains an archiveReader, returning every entry's name to
+// its content.
+func readAllEntries(t testing.TB, reader archiveReader) map[string][]byte {
+	t.Helper()
+	defer reader.Close()
+
+	got := map[string][]byte{}
+	for {
+		f, err := reader.Next()
+		if err != nil {
+			break
+		}
+		data := make([]byte, 0, f.Size())
+		buf := make([]byte, 4096)
+		for {
+			n, rerr := f.Read(buf)
+			data = append(data, buf[:n]...)
+			if rerr != nil {
+				break
+			}
+		}
+		got[f.Name()] = data
+	}
+	return got
+}
+
+func assertEntriesMatch(t testing.TB, got, want map[string][]byte) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d (%v)", len(got), len(want), got)
+	}
+	for name, data := range want {
+		gotData, ok := got[name]
+		if !ok {
+			t.Fatalf("missing entry %q", name)
+		}
+		if string(gotData) != string(data) {
+			t.Fatalf("entry %q content mismatch: got %q, want %q", name, gotData, data)
+		}
+	}
+}
+
+func TestOpenArchive_TarGz(t *testing.T) {
+	files := fixtureFiles(t)
+	path := filepath.Join(t.TempDir(), "package.tar.gz")
+	writeTarGzFixture(t, path, files)
+
+	reader, err := openArchive(path, "")
+	if err != nil {
+		t.Fatalf("error opening package: %v", err)
+	}
+	assertEntriesMatch(t, readAllEntries(t, reader), files)
+}
+
+func TestOpenArchive_Tar(t *testing.T) {
+	files := fixtureFiles(t)
+	path := filepath.Join(t.TempDir(), "package.tar")
+	writeTarFixture(t, path, files)
+
+	reader, err := openArchive(path, "")
+	if err != nil {
+		t.Fatalf("error opening package: %v", err)
+	}
+	assertEntriesMatch(t, readAllEntries(t, reader), files)
+}
+
+func TestOpenArchive_Zip(t *testing.T) {
+	files := fixtureFiles(t)
+	path := filepath.Join(t.TempDir(), "package.zip")
+	writeZipFixture(t, path, files)
+
+	reader, err := openArchive(path, "")
+	if err != nil {
+		t.Fatalf("error opening package: %v", err)
+	}
+	assertEntriesMatch(t, readAllEntries(t, reader), files)
+}
+
+// TestOpenArchive_TarBz2 exercises a precompressed fixture, since the
+// standard library's compress/bzip2 only decodes (it has no writer), so a
+// tar.bz2 test package can't be built on the fly the way the other formats
+// are above.
+func TestOpenArchive_TarBz2(t *testing.T) {
+	files := fixtureFiles(t)
+
+	reader, err := openArchive(filepath.Join("testdata", "package.tar.bz2"), "")
+	if err != nil {
+		t.Fatalf("error opening package: %v", err)
+	}
+	assertEntriesMatch(t, readAllEntries(t, reader), files)
+}
+
+// TestOpenArchive_TarXz exercises a precompressed fixture, since
+// github.com/ulikunitz/xz is decode-only, so a tar.xz test package can't be
+// built on the fly the way the other formats are above.
+func TestOpenArchive_TarXz(t *testing.T) {
+	files := fixtureFiles(t)
+
+	reader, err := openArchive(filepath.Join("testdata", "package.tar.xz"), "")
+	if err != nil {
+		t.Fatalf("error opening package: %v", err)
+	}
+	assertEntriesMatch(t, readAllEntries(t, reader), files)
+}
+
+func TestValidate_AllFormats(t *testing.T) {
+	files := fixtureFiles(t)
+
+	for _, tc := range []struct {
+		name string
+	}{
+		{"tar.gz"},
+		{"tar"},
+		{"zip"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "package."+tc.name)
+			switch tc.name {
+			case "tar.gz":
+				writeTarGzFixture(t, path, files)
+			case "tar":
+				writeTarFixture(t, path, files)
+			case "zip":
+				writeZipFixture(t, path, files)
+			}
+
+			report := NewValidationReport()
+			if _, err := validate(path, "", nil, report, false, DefaultMaxFileSize, 0); err != nil {
+				t.Fatalf("error validating %s package: %v", tc.name, err)
+			}
+		})
+	}
+}
+
+func BenchmarkValidate_TarGz(b *testing.B) {
+	files := map[string][]byte{
+		"fastly.toml": []byte("name = \"demo\"\nlanguage = \"rust\"\nmanifest_version = 2\nauthors = [\"demo@example.com\"]\n"),
+		"main.wasm":   validWasmModule,
+	}
+
+	dir := b.TempDir()
+	path := filepath.Join(dir, "package.tar.gz")
+	writeTarGzFixture(b, path, files)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		report := NewValidationReport()
+		if _, err := validate(path, "", nil, report, false, DefaultMaxFileSize, 0); err != nil {
+			b.Fatalf("error validating package: %v", err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package compute
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// MaxPackageSize is the maximum size, in bytes, of a package accepted by the
+// Fastly Compute@Edge platform.
+const MaxPackageSize = 100 * 1024 * 1024
+
+// hashEntry captures just enough about an archive member to feed the
+// aggregate digest deterministically.
+type hashEntry struct {
+	name string
+	data []byte
+}
+
+// aggregateHash computes the SHA-512 digest of a package from its entries.
+// Entries are hashed in sorted-name order so the digest is reproducible
+// across packaging tools that may store archive members in a different
+// order: for each entry, in turn, its name, a NUL byte, its size, a NUL byte
+// and its content are written into the hash.
+func aggregateHash(entries []hashEntry) string {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+
+	h := sha512.New()
+	for _, e := range entries {
+		fmt.Fprintf(h, "%s\x00%d\x00", e.name, len(e.data))
+		h.Write(e.data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
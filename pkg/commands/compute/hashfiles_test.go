@@ -0,0 +1,47 @@
+package compute
+
+import "testing"
+
+// knownGoodDigest is the expected sha512:<hex> for the fixed set of entries
+// below, computed independently (sorted by name, each entry hashed as
+// name + NUL + decimal size + NUL + content) so this test catches any
+// accidental change to the digest algorithm rather than just checking it
+// against itself.
+const knownGoodDigest = "c82ff576a04bf791bf1b588b708f852182e5a7782d9e90a2d5670bfa1c8ee786f5263cc15d434518af3366d74f9e938f4f5772c6df2ccd7de11393a711367203"
+
+func fixedPackageEntries() []hashEntry {
+	return []hashEntry{
+		{name: "fastly.toml", data: []byte("name = \"demo\"\n")},
+		{name: "main.wasm", data: []byte("\x00asm\x01\x00\x00\x00")},
+	}
+}
+
+func TestAggregateHash_KnownGood(t *testing.T) {
+	got := aggregateHash(fixedPackageEntries())
+	if got != knownGoodDigest {
+		t.Fatalf("aggregateHash mismatch:\n got  %s\n want %s", got, knownGoodDigest)
+	}
+}
+
+// TestAggregateHash_OrderIndependent asserts the digest is derived from
+// sorted-name order regardless of the order entries were appended in, since
+// different packaging tools may stream archive members in different
+// orders.
+func TestAggregateHash_OrderIndependent(t *testing.T) {
+	entries := fixedPackageEntries()
+	reversed := []hashEntry{entries[1], entries[0]}
+
+	if aggregateHash(entries) != aggregateHash(reversed) {
+		t.Fatal("expected aggregateHash to be independent of entry order")
+	}
+}
+
+func TestAggregateHash_ContentChangeAltersDigest(t *testing.T) {
+	entries := fixedPackageEntries()
+	changed := fixedPackageEntries()
+	changed[1].data = append(changed[1].data, 0x00)
+
+	if aggregateHash(entries) == aggregateHash(changed) {
+		t.Fatal("expected a content change to alter the digest")
+	}
+}
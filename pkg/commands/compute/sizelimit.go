@@ -0,0 +1,35 @@
+package compute
+
+import "fmt"
+
+// DefaultMaxFileSize is the default per-file uncompressed size limit
+// enforced while validating a package, overridable via --max-file-size.
+const DefaultMaxFileSize = 100 * 1024 * 1024
+
+// sizeLimiter is an io.Writer that tracks how many uncompressed bytes have
+// been read for a single archive entry, as well as the running total across
+// the whole archive, failing fast once either configured limit is exceeded.
+// It exists so a decompression-bomb style archive is rejected mid-stream
+// instead of being read fully into memory first.
+type sizeLimiter struct {
+	name     string
+	maxFile  int64
+	maxTotal int64
+	fileSize int64
+	total    *int64
+}
+
+// Write implements the io.Writer interface.
+func (w *sizeLimiter) Write(p []byte) (int, error) {
+	w.fileSize += int64(len(p))
+	if w.maxFile > 0 && w.fileSize > w.maxFile {
+		return 0, fmt.Errorf("error validating package: file %q exceeds the maximum per-file size of %d bytes (observed at least %d bytes)", w.name, w.maxFile, w.fileSize)
+	}
+
+	*w.total += int64(len(p))
+	if w.maxTotal > 0 && *w.total > w.maxTotal {
+		return 0, fmt.Errorf("error validating package: total uncompressed size exceeds the maximum of %d bytes while reading %q", w.maxTotal, w.name)
+	}
+
+	return len(p), nil
+}
@@ -1,18 +1,19 @@
 package compute
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 
+	"github.com/BurntSushi/toml"
 	"github.com/fastly/cli/pkg/cmd"
 	"github.com/fastly/cli/pkg/config"
 	fsterr "github.com/fastly/cli/pkg/errors"
 	"github.com/fastly/cli/pkg/manifest"
 	"github.com/fastly/cli/pkg/text"
 	"github.com/kennygrant/sanitize"
-	"github.com/mholt/archiver/v3"
 )
 
 // NewValidateCommand returns a usable command registered under the parent.
@@ -21,7 +22,15 @@ func NewValidateCommand(parent cmd.Registerer, globals *config.Data, data manife
 	c.Globals = globals
 	c.manifest = data
 	c.CmdClause = parent.Command("validate", "Validate a Compute@Edge package")
-	c.CmdClause.Flag("package", "Path to a package tar.gz").Short('p').StringVar(&c.path)
+	c.CmdClause.Flag("package", "Path to a package (tar.gz, zip, tar, tar.xz or tar.bz2)").Short('p').StringVar(&c.path)
+	c.CmdClause.Flag("strict", "Treat validation warnings as errors").BoolVar(&c.strict)
+	c.CmdClause.Flag("print-hash", "Print the SHA-512 digest of the package contents").BoolVar(&c.printHash)
+	c.CmdClause.Flag("expected-hash", "Fail validation unless the package's digest matches sha512:<hex>").StringVar(&c.expectedHash)
+	c.CmdClause.Flag("write-hash", "Write the package's SHA-512 digest to a <package>.sha512 sidecar file").BoolVar(&c.writeHash)
+	c.CmdClause.Flag("format", "Package archive format: tar.gz, zip, tar, tar.xz or tar.bz2 (default: auto-detected)").StringVar(&c.format)
+	c.CmdClause.Flag("max-file-size", "Maximum uncompressed size, in bytes, accepted for any single file in the package").Default(fmt.Sprint(DefaultMaxFileSize)).Int64Var(&c.maxFileSize)
+	c.CmdClause.Flag("max-uncompressed-size", "Maximum total uncompressed size, in bytes, accepted for the package (0 disables the check)").Int64Var(&c.maxUncompressedSize)
+	c.CmdClause.Flag("wasm-target", "Assert that main.wasm targets wasm32-wasi").BoolVar(&c.wasmTarget)
 	return &c
 }
 
@@ -47,7 +56,22 @@ func (c *ValidateCommand) Exec(_ io.Reader, out io.Writer) error {
 		return fmt.Errorf("error reading file path: %w", err)
 	}
 
-	if err := validate(p, nil); err != nil {
+	info, err := os.Stat(p)
+	if err != nil {
+		return fmt.Errorf("error reading package: %w", err)
+	}
+	if info.Size() > MaxPackageSize {
+		return fsterr.RemediationError{
+			Inner:       fmt.Errorf("package size (%d bytes) exceeds the maximum accepted by Compute@Edge (%d bytes)", info.Size(), MaxPackageSize),
+			Remediation: "Remove unnecessary files from the package and rebuild, or split functionality across multiple services.",
+		}
+	}
+
+	collectHash := c.printHash || c.expectedHash != "" || c.writeHash
+
+	report := NewValidationReport()
+	entries, err := validate(p, c.format, validateWasmModule(c.wasmTarget), report, collectHash, c.maxFileSize, c.maxUncompressedSize)
+	if err != nil {
 		c.Globals.ErrLog.AddWithContext(err, map[string]any{
 			"Path": c.path,
 		})
@@ -57,6 +81,34 @@ func (c *ValidateCommand) Exec(_ io.Reader, out io.Writer) error {
 		}
 	}
 
+	if collectHash {
+		digest := "sha512:" + aggregateHash(entries)
+
+		if c.expectedHash != "" && digest != c.expectedHash {
+			return fsterr.RemediationError{
+				Inner:       fmt.Errorf("package digest mismatch: got %s, expected %s", digest, c.expectedHash),
+				Remediation: "Rebuild the package and re-run `fastly compute validate`, or double check the --expected-hash value.",
+			}
+		}
+		if c.printHash {
+			fmt.Fprintln(out, digest)
+		}
+		if c.writeHash {
+			if err := os.WriteFile(p+".sha512", []byte(digest+"\n"), 0o644); err != nil {
+				return fmt.Errorf("error writing hash sidecar file: %w", err)
+			}
+		}
+	}
+
+	report.Print(out)
+
+	if report.Failed(c.strict) {
+		return fsterr.RemediationError{
+			Inner:       fmt.Errorf("package failed validation: %d error(s), %d warning(s)", report.CountBySeverity(SeverityError), report.CountBySeverity(SeverityWarning)),
+			Remediation: "Address the errors listed above (or the warnings too, if running with --strict) and re-run `fastly compute validate`.",
+		}
+	}
+
 	text.Success(out, "Validated package %s", p)
 	return nil
 }
@@ -64,48 +116,196 @@ func (c *ValidateCommand) Exec(_ io.Reader, out io.Writer) error {
 // ValidateCommand validates a package archive.
 type ValidateCommand struct {
 	cmd.Base
-	manifest manifest.Data
-	path     string
+	manifest            manifest.Data
+	path                string
+	strict              bool
+	printHash           bool
+	expectedHash        string
+	writeHash           bool
+	format              string
+	maxFileSize         int64
+	maxUncompressedSize int64
+	wasmTarget          bool
 }
 
 // FileValidator validates a file.
-type FileValidator func(archiver.File) error
+type FileValidator func(File) error
+
+// Severity classifies how serious a validation finding is.
+type Severity int
+
+const (
+	// SeverityWarning indicates a finding that's surfaced to the user but
+	// doesn't by itself fail validation, unless --strict is set.
+	SeverityWarning Severity = iota
+	// SeverityError indicates a finding that fails validation.
+	SeverityError
+)
+
+// String implements the fmt.Stringer interface.
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "unknown"
+	}
+}
+
+// ValidationFinding is a single check outcome recorded against a
+// ValidationReport.
+type ValidationFinding struct {
+	Check    string
+	Message  string
+	Severity Severity
+}
+
+// ValidationReport accumulates the outcome of every check performed while
+// validating a package, so the caller can present a full summary instead of
+// failing on the first problem encountered.
+type ValidationReport struct {
+	Findings []ValidationFinding
+}
+
+// NewValidationReport returns a usable ValidationReport.
+func NewValidationReport() *ValidationReport {
+	return &ValidationReport{}
+}
+
+// Error records a failing check.
+func (r *ValidationReport) Error(check, format string, args ...any) {
+	r.Findings = append(r.Findings, ValidationFinding{
+		Check:    check,
+		Message:  fmt.Sprintf(format, args...),
+		Severity: SeverityError,
+	})
+}
+
+// Warning records a non-fatal check.
+func (r *ValidationReport) Warning(check, format string, args ...any) {
+	r.Findings = append(r.Findings, ValidationFinding{
+		Check:    check,
+		Message:  fmt.Sprintf(format, args...),
+		Severity: SeverityWarning,
+	})
+}
+
+// CountBySeverity returns the number of findings recorded at the given
+// severity.
+func (r *ValidationReport) CountBySeverity(s Severity) int {
+	var n int
+	for _, f := range r.Findings {
+		if f.Severity == s {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed reports whether the accumulated findings should cause validation to
+// fail. Errors always fail; warnings only fail when strict is true.
+func (r *ValidationReport) Failed(strict bool) bool {
+	if r.CountBySeverity(SeverityError) > 0 {
+		return true
+	}
+	return strict && r.CountBySeverity(SeverityWarning) > 0
+}
+
+// Print writes a per-check summary, followed by an error/warning count, to
+// out. It's a no-op if no findings were recorded.
+func (r *ValidationReport) Print(out io.Writer) {
+	if len(r.Findings) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "\nValidation summary:")
+	for _, f := range r.Findings {
+		fmt.Fprintf(out, "  [%s] %s: %s\n", f.Severity, f.Check, f.Message)
+	}
+	fmt.Fprintf(out, "%d error(s), %d warning(s)\n", r.CountBySeverity(SeverityError), r.CountBySeverity(SeverityWarning))
+}
+
+// requiredManifestFields validates the decoded fastly.toml content against
+// the fields a Compute@Edge package is expected to carry, recording a
+// finding for each problem rather than stopping at the first one.
+func requiredManifestFields(data []byte, report *ValidationReport) {
+	var m manifest.File
+	if _, err := toml.Decode(string(data), &m); err != nil {
+		report.Error("fastly.toml", "unable to parse manifest: %s", err)
+		return
+	}
+
+	if m.Name == "" {
+		report.Error("fastly.toml", "missing required field %q", "name")
+	}
+	if m.Language == "" {
+		report.Error("fastly.toml", "missing required field %q", "language")
+	}
+	if m.ManifestVersion == 0 {
+		report.Error("fastly.toml", "missing required field %q", "manifest_version")
+	}
+	if len(m.Authors) == 0 {
+		report.Warning("fastly.toml", "missing recommended field %q", "authors")
+	}
+	if m.ServiceID == "" {
+		report.Warning("fastly.toml", "%q is not set; the package can be validated but not deployed without it", "service_id")
+	}
+}
 
 // validate is a utility function to determine whether a package is valid.
-// It attempts to unarchive and read a tar.gz file from a specific path,
+// It attempts to unarchive and read a package archive from a specific path,
 // if successful, it then iterates through (streams) each file in the archive
 // checking the filename against a list of required files. If one of the files
 // doesn't exist it returns an error.
 // validate also call fileValidator, if not nil, passing the file obtained from
-// tar.Read().
+// reader.Next().
 //
-// NOTE: This function is also called by the `deploy` command.
-func validate(path string, fileValidator FileValidator) error {
-	file, err := os.Open(filepath.Clean(path))
-	if err != nil {
-		return fmt.Errorf("error reading package: %w", err)
-	}
-	defer file.Close() // #nosec G307
-
-	tar := archiver.NewTarGz()
-	err = tar.Open(file, 0)
+// Archives are read using the standard library (archive/tar, archive/zip,
+// compress/gzip, compress/bzip2) plus github.com/ulikunitz/xz for tar.xz,
+// rather than github.com/mholt/archiver, to avoid that module's large
+// transitive dependency tree for formats this command has no need of.
+//
+// Beyond file presence, validate parses fastly.toml and records deeper
+// manifest checks (required and recommended fields) onto report, so the
+// caller can decide, based on severity, whether the package is acceptable.
+//
+// When collectHash is true, validate also returns the buffered content of
+// every archive entry so the caller can derive an aggregate digest of the
+// package (see aggregateHash in hashfiles.go).
+//
+// format selects the archive format to unarchive path with: tar.gz, zip,
+// tar, tar.xz or tar.bz2. An empty format auto-detects from the package's
+// header, falling back to its file extension; see openArchive in
+// archive.go.
+//
+// maxFileSize and maxUncompressedSize bound, respectively, the uncompressed
+// size of any single archive entry and the running total across the whole
+// archive; either being zero disables that check. Every entry is streamed
+// through these limits as it's read, so a decompression-bomb style archive
+// is rejected mid-stream rather than exhausting memory first.
+func validate(path, format string, fileValidator FileValidator, report *ValidationReport, collectHash bool, maxFileSize, maxUncompressedSize int64) ([]hashEntry, error) {
+	reader, err := openArchive(path, format)
 	if err != nil {
-		return fmt.Errorf("error unarchiving package: %w", err)
+		return nil, err
 	}
-	defer tar.Close()
+	defer reader.Close()
 
 	files := map[string]bool{
 		"fastly.toml": false,
 		"main.wasm":   false,
 	}
 
+	var entries []hashEntry
+	var totalSize int64
+
 	for {
-		f, err := tar.Read()
+		f, err := reader.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("error reading package: %w", err)
+			return nil, fmt.Errorf("error reading package: %w", err)
 		}
 
 		for k := range files {
@@ -114,24 +314,36 @@ func validate(path string, fileValidator FileValidator) error {
 			}
 		}
 
-		if fileValidator != nil {
-			if err = fileValidator(f); err != nil {
-				f.Close()
-				return err
-			}
+		// Every entry is read fully (and counted against the size limits)
+		// regardless of whether its content is needed, so fileValidator
+		// below always sees the complete file rather than whatever bytes
+		// happen to be left unread by the checks above.
+		var buf bytes.Buffer
+		limiter := &sizeLimiter{name: f.Name(), maxFile: maxFileSize, maxTotal: maxUncompressedSize, total: &totalSize}
+		if _, err := io.Copy(io.MultiWriter(&buf, limiter), f); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", f.Name(), err)
 		}
 
-		err = f.Close()
-		if err != nil {
-			return fmt.Errorf("error closing file: %w", err)
+		if f.Name() == "fastly.toml" {
+			requiredManifestFields(buf.Bytes(), report)
+		}
+		if collectHash {
+			entries = append(entries, hashEntry{name: f.Name(), data: buf.Bytes()})
+		}
+
+		if fileValidator != nil {
+			buffered := File{name: f.Name(), size: f.Size(), mode: f.Mode(), Reader: bytes.NewReader(buf.Bytes())}
+			if err = fileValidator(buffered); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	for k, found := range files {
 		if !found {
-			return fmt.Errorf("error validating package: package must contain a %s file", k)
+			return nil, fmt.Errorf("error validating package: package must contain a %s file", k)
 		}
 	}
 
-	return nil
+	return entries, nil
 }
@@ -0,0 +1,306 @@
+package compute
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+var (
+	wasmMagic   = []byte{0x00, 'a', 's', 'm'}
+	wasmVersion = []byte{0x01, 0x00, 0x00, 0x00}
+)
+
+// WebAssembly section ids, as defined by the binary format spec.
+const (
+	wasmSecImport = 2
+	wasmSecMemory = 5
+	wasmSecExport = 7
+)
+
+// WebAssembly external_kind values used in the import and export sections.
+const (
+	wasmExternalKindFunc = iota
+	wasmExternalKindTable
+	wasmExternalKindMemory
+	wasmExternalKindGlobal
+)
+
+// validateWasmModule returns a FileValidator that structurally validates
+// main.wasm: its magic number and version, well-formed section headers, and
+// (when wasmTarget is true) that it imports from wasi_snapshot_preview1.
+// Any other file in the package is passed through untouched.
+func validateWasmModule(wasmTarget bool) FileValidator {
+	return func(f File) error {
+		if f.Name() != "main.wasm" {
+			return nil
+		}
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return fmt.Errorf("invalid main.wasm: unable to read module: %w", err)
+		}
+		return validateWasmBytes(data, wasmTarget)
+	}
+}
+
+// validateWasmBytes implements the checks described on validateWasmModule
+// against the full, already-buffered bytes of a wasm module.
+//
+// Every section is bounds-checked against the remaining length of data
+// before it's sliced out, so a corrupted or hostile module that claims an
+// implausibly large section size fails immediately with a "malformed
+// section" error rather than driving an allocation sized off that
+// untrusted value.
+func validateWasmBytes(data []byte, wasmTarget bool) error {
+	if len(data) < 8 {
+		return fmt.Errorf("invalid main.wasm: file is too small to contain a WebAssembly header")
+	}
+
+	if !bytes.Equal(data[0:4], wasmMagic) {
+		return fmt.Errorf("invalid main.wasm: bad magic number %x, expected the WebAssembly magic \\0asm", data[0:4])
+	}
+	if !bytes.Equal(data[4:8], wasmVersion) {
+		return fmt.Errorf("invalid main.wasm: unsupported version %x, expected the MVP binary version 01 00 00 00", data[4:8])
+	}
+
+	var (
+		hasMemoryImport  bool
+		hasMemorySection bool
+		hasStartExport   bool
+		hasWasiImport    bool
+	)
+
+	pos := 8
+	for pos < len(data) {
+		id := data[pos]
+		pos++
+
+		size, n, err := readVarUint32(data[pos:])
+		if err != nil {
+			return fmt.Errorf("invalid main.wasm: malformed section (id %d): unable to read size: %w", id, err)
+		}
+		pos += n
+
+		if int64(size) > int64(len(data)-pos) {
+			return fmt.Errorf("invalid main.wasm: malformed section (id %d): declared size %d exceeds the %d bytes remaining in the module", id, size, len(data)-pos)
+		}
+		body := data[pos : pos+int(size)]
+		pos += int(size)
+
+		switch id {
+		case wasmSecImport:
+			imports, err := parseWasmImportSection(body)
+			if err != nil {
+				return fmt.Errorf("invalid main.wasm: malformed import section: %w", err)
+			}
+			for _, imp := range imports {
+				if imp.kind == wasmExternalKindMemory {
+					hasMemoryImport = true
+				}
+				if imp.module == "wasi_snapshot_preview1" {
+					hasWasiImport = true
+				}
+			}
+		case wasmSecMemory:
+			hasMemorySection = true
+		case wasmSecExport:
+			exports, err := parseWasmExportSection(body)
+			if err != nil {
+				return fmt.Errorf("invalid main.wasm: malformed export section: %w", err)
+			}
+			for _, name := range exports {
+				if name == "_start" || name == "main" {
+					hasStartExport = true
+				}
+			}
+		}
+	}
+
+	if !hasMemoryImport && !hasMemorySection {
+		return fmt.Errorf("invalid main.wasm: no Memory import or Memory section found; Compute@Edge modules must define or import linear memory")
+	}
+	if !hasStartExport {
+		return fmt.Errorf("invalid main.wasm: no exported _start or main function found")
+	}
+	if wasmTarget && !hasWasiImport {
+		return fmt.Errorf("invalid main.wasm: --wasm-target=wasm32-wasi requires an import from the wasi_snapshot_preview1 module")
+	}
+
+	return nil
+}
+
+// wasmImport is the subset of a WebAssembly import_entry this package
+// cares about.
+type wasmImport struct {
+	module string
+	kind   byte
+}
+
+// parseWasmImportSection decodes the body of an import section (id 2) into
+// its entries, skipping over the kind-specific payload of each one. Every
+// read is bounds-checked against body, since it comes straight from the
+// package under validation.
+func parseWasmImportSection(body []byte) ([]wasmImport, error) {
+	count, pos, err := readVarUint32(body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read import count: %w", err)
+	}
+	if int64(count) > int64(len(body)-pos) {
+		return nil, fmt.Errorf("declared import count %d exceeds the %d bytes remaining in the section", count, len(body)-pos)
+	}
+
+	imports := make([]wasmImport, 0, count)
+	for i := uint32(0); i < count; i++ {
+		module, n, err := readWasmString(body[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("import %d: unable to read module name: %w", i, err)
+		}
+		pos += n
+
+		_, n, err = readWasmString(body[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("import %d: unable to read field name: %w", i, err)
+		}
+		pos += n
+
+		if pos >= len(body) {
+			return nil, fmt.Errorf("import %d: unexpected end of section while reading external kind", i)
+		}
+		kind := body[pos]
+		pos++
+
+		n, err = skipWasmImportDescriptor(body[pos:], kind)
+		if err != nil {
+			return nil, fmt.Errorf("import %d: %w", i, err)
+		}
+		pos += n
+
+		imports = append(imports, wasmImport{module: module, kind: kind})
+	}
+
+	return imports, nil
+}
+
+// skipWasmImportDescriptor consumes the kind-specific payload following an
+// import's external_kind byte, without interpreting it further, and returns
+// the number of bytes consumed.
+func skipWasmImportDescriptor(b []byte, kind byte) (int, error) {
+	switch kind {
+	case wasmExternalKindFunc:
+		_, n, err := readVarUint32(b) // type index
+		return n, err
+	case wasmExternalKindTable:
+		if len(b) < 1 {
+			return 0, fmt.Errorf("unexpected end of section while reading elem_type")
+		}
+		n, err := skipWasmLimits(b[1:])
+		return n + 1, err
+	case wasmExternalKindMemory:
+		return skipWasmLimits(b)
+	case wasmExternalKindGlobal:
+		if len(b) < 2 {
+			return 0, fmt.Errorf("unexpected end of section while reading global type")
+		}
+		return 2, nil
+	default:
+		return 0, fmt.Errorf("unrecognised external kind %d", kind)
+	}
+}
+
+// skipWasmLimits consumes a resizable_limits structure: a flags byte
+// followed by an initial size, and a maximum size if the flags bit is set.
+// It returns the number of bytes consumed.
+func skipWasmLimits(b []byte) (int, error) {
+	if len(b) < 1 {
+		return 0, fmt.Errorf("unexpected end of section while reading limits")
+	}
+	flags := b[0]
+	pos := 1
+
+	_, n, err := readVarUint32(b[pos:]) // initial
+	if err != nil {
+		return 0, fmt.Errorf("unable to read limits: %w", err)
+	}
+	pos += n
+
+	if flags&0x1 != 0 {
+		_, n, err := readVarUint32(b[pos:]) // maximum
+		if err != nil {
+			return 0, fmt.Errorf("unable to read limits: %w", err)
+		}
+		pos += n
+	}
+
+	return pos, nil
+}
+
+// parseWasmExportSection decodes the body of an export section (id 7) into
+// the list of exported names.
+func parseWasmExportSection(body []byte) ([]string, error) {
+	count, pos, err := readVarUint32(body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read export count: %w", err)
+	}
+	if int64(count) > int64(len(body)-pos) {
+		return nil, fmt.Errorf("declared export count %d exceeds the %d bytes remaining in the section", count, len(body)-pos)
+	}
+
+	names := make([]string, 0, count)
+	for i := uint32(0); i < count; i++ {
+		name, n, err := readWasmString(body[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("export %d: unable to read field name: %w", i, err)
+		}
+		pos += n
+
+		if pos >= len(body) {
+			return nil, fmt.Errorf("export %d: unexpected end of section while reading external kind", i)
+		}
+		pos++ // external kind
+
+		_, n, err = readVarUint32(body[pos:]) // index
+		if err != nil {
+			return nil, fmt.Errorf("export %d: unable to read index: %w", i, err)
+		}
+		pos += n
+
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// readWasmString reads a length-prefixed UTF-8 string, as used for names
+// throughout the WebAssembly binary format, and returns the number of bytes
+// consumed (including its length prefix).
+func readWasmString(b []byte) (string, int, error) {
+	n, pos, err := readVarUint32(b)
+	if err != nil {
+		return "", 0, err
+	}
+	if int64(n) > int64(len(b)-pos) {
+		return "", 0, fmt.Errorf("declared string length %d exceeds the %d bytes remaining", n, len(b)-pos)
+	}
+	return string(b[pos : pos+int(n)]), pos + int(n), nil
+}
+
+// readVarUint32 decodes an unsigned LEB128-encoded uint32 from the start of
+// b, the integer encoding used throughout the WebAssembly binary format for
+// section sizes, counts and indices. It returns the decoded value and the
+// number of bytes consumed.
+func readVarUint32(b []byte) (value uint32, n int, err error) {
+	var shift uint
+	for n < len(b) {
+		c := b[n]
+		value |= uint32(c&0x7f) << shift
+		n++
+		if c&0x80 == 0 {
+			return value, n, nil
+		}
+		shift += 7
+		if shift >= 32 {
+			return 0, 0, fmt.Errorf("LEB128 varuint32 overflow")
+		}
+	}
+	return 0, 0, fmt.Errorf("unexpected end of input while reading a LEB128 integer")
+}
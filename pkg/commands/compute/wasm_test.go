@@ -0,0 +1,126 @@
+package compute
+
+import (
+	"strings"
+	"testing"
+)
+
+// validWasmModule is a minimal, hand-assembled WebAssembly module: the
+// header, a Memory section declaring one page of linear memory, and an
+// Export section exporting a "_start" function. It satisfies every check
+// validateWasmBytes performs except --wasm-target, since it has no Import
+// section at all.
+var validWasmModule = []byte{
+	0x00, 'a', 's', 'm', // magic
+	0x01, 0x00, 0x00, 0x00, // version
+
+	0x05, 0x03, 0x01, 0x00, 0x01, // section 5 (Memory), size 3: count=1, flags=0, initial=1
+
+	0x07, 0x0a, // section 7 (Export), size 10
+	0x01,                               // count=1
+	0x06, '_', 's', 't', 'a', 'r', 't', // name "_start"
+	0x00, // external_kind=func
+	0x00, // function index 0
+}
+
+func TestValidateWasmBytes_Valid(t *testing.T) {
+	if err := validateWasmBytes(validWasmModule, false); err != nil {
+		t.Fatalf("expected a valid module to pass, got: %v", err)
+	}
+}
+
+func TestValidateWasmBytes_BadMagic(t *testing.T) {
+	m := append([]byte{}, validWasmModule...)
+	m[0] = 0xff
+	if err := validateWasmBytes(m, false); err == nil || !strings.Contains(err.Error(), "bad magic number") {
+		t.Fatalf("expected a bad magic number error, got: %v", err)
+	}
+}
+
+func TestValidateWasmBytes_TooSmall(t *testing.T) {
+	if err := validateWasmBytes(validWasmModule[:4], false); err == nil || !strings.Contains(err.Error(), "too small") {
+		t.Fatalf("expected a too-small error, got: %v", err)
+	}
+}
+
+// TestValidateWasmBytes_OversizedSection is a regression test: a section
+// that declares a size far larger than the bytes actually remaining in the
+// module must fail immediately with a bounds error, rather than attempting
+// to allocate a buffer sized off the untrusted declared size.
+func TestValidateWasmBytes_OversizedSection(t *testing.T) {
+	corrupt := []byte{
+		0x00, 'a', 's', 'm',
+		0x01, 0x00, 0x00, 0x00,
+		0x05,       // section 5 (Memory)
+		0x90, 0x4e, // declared size: 10000, but no body bytes follow
+	}
+
+	err := validateWasmBytes(corrupt, false)
+	if err == nil {
+		t.Fatal("expected an error for a truncated, oversized section")
+	}
+	if !strings.Contains(err.Error(), "malformed section") || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("expected a malformed-section bounds error, got: %v", err)
+	}
+}
+
+// TestValidateWasmBytes_OversizedImportCount is a regression test: an
+// Import section whose declared entry count vastly exceeds the bytes
+// actually available in its (already bounds-checked) body must fail
+// immediately with a bounds error, rather than allocating a slice sized off
+// the untrusted declared count.
+func TestValidateWasmBytes_OversizedImportCount(t *testing.T) {
+	corrupt := []byte{
+		0x00, 'a', 's', 'm',
+		0x01, 0x00, 0x00, 0x00,
+		0x02, 0x05, // section 2 (Import), size 5
+		0xff, 0xff, 0xff, 0xff, 0x0f, // count: 0xffffffff
+	}
+
+	err := validateWasmBytes(corrupt, false)
+	if err == nil {
+		t.Fatal("expected an error for an oversized import count")
+	}
+	if !strings.Contains(err.Error(), "import count") || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("expected an import-count bounds error, got: %v", err)
+	}
+}
+
+func TestValidateWasmBytes_MissingMemory(t *testing.T) {
+	m := []byte{
+		0x00, 'a', 's', 'm',
+		0x01, 0x00, 0x00, 0x00,
+		0x07, 0x0a,
+		0x01,
+		0x06, '_', 's', 't', 'a', 'r', 't',
+		0x00,
+		0x00,
+	}
+	if err := validateWasmBytes(m, false); err == nil || !strings.Contains(err.Error(), "Memory") {
+		t.Fatalf("expected a missing-Memory error, got: %v", err)
+	}
+}
+
+func TestValidateWasmBytes_MissingStartExport(t *testing.T) {
+	m := []byte{
+		0x00, 'a', 's', 'm',
+		0x01, 0x00, 0x00, 0x00,
+		0x05, 0x03, 0x01, 0x00, 0x01,
+	}
+	if err := validateWasmBytes(m, false); err == nil || !strings.Contains(err.Error(), "_start") {
+		t.Fatalf("expected a missing-export error, got: %v", err)
+	}
+}
+
+func TestValidateWasmBytes_WasmTargetRequiresWasi(t *testing.T) {
+	if err := validateWasmBytes(validWasmModule, true); err == nil || !strings.Contains(err.Error(), "wasi_snapshot_preview1") {
+		t.Fatalf("expected a wasm-target error, got: %v", err)
+	}
+}
+
+func TestValidateWasmModule_SkipsOtherFiles(t *testing.T) {
+	validator := validateWasmModule(false)
+	if err := validator(File{name: "fastly.toml"}); err != nil {
+		t.Fatalf("expected non-wasm files to be skipped, got: %v", err)
+	}
+}